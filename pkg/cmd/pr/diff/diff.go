@@ -0,0 +1,363 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/cli/cli/api"
+	"github.com/cli/cli/context"
+	"github.com/cli/cli/git"
+	"github.com/cli/cli/internal/ghrepo"
+	"github.com/cli/cli/pkg/cmd/pr/shared"
+	"github.com/cli/cli/pkg/cmdutil"
+	"github.com/cli/cli/pkg/iostreams"
+	"github.com/spf13/cobra"
+)
+
+type DiffOptions struct {
+	HttpClient func() (*http.Client, error)
+	IO         *iostreams.IOStreams
+	BaseRepo   func() (ghrepo.Interface, error)
+	Branch     func() (string, error)
+	Remotes    func() (context.Remotes, error)
+
+	SelectorArg string
+	UseColor    string
+
+	// Local diffs the current branch against a base ref using the local
+	// git repository instead of fetching the patch from the API.
+	Local      bool
+	BaseBranch string
+	HeadBranch string
+
+	// Style controls how the diff is rendered: unified, side-by-side, or word.
+	Style string
+	// Context is the number of unchanged lines to show around each change,
+	// or -1 to leave the diff's own hunk context untouched.
+	Context int
+
+	// Highlight controls syntax highlighting of hunk content: auto, never,
+	// or always. Theme names a chroma style; when empty it is resolved
+	// based on whether stdout is a TTY.
+	Highlight string
+	Theme     string
+
+	// Format, when non-empty, emits the diff as a format-patch-style
+	// message series (patch or mbox, one message per commit) or as a SARIF
+	// report (sarif) instead of a plain diff.
+	Format string
+	// Apply, when non-empty, applies the diff to the working tree with
+	// `git apply` instead of printing it. A value of "3way" requests a
+	// 3-way merge fallback.
+	Apply string
+
+	// Paths and Excludes glob-filter files by their "b/" path; a file must
+	// match at least one Paths pattern (if any are given) and no Excludes
+	// pattern to be kept.
+	Paths        []string
+	Excludes     []string
+	OnlyAdded    bool
+	OnlyModified bool
+	// Stat renders a diffstat-style summary instead of the diff itself.
+	Stat bool
+
+	Exporter cmdutil.Exporter
+}
+
+var runPager = func(pager string, diff io.Reader, out io.Writer) error {
+	pagerCmd := exec.Command("sh", "-c", pager)
+	pagerCmd.Stdin = diff
+	pagerCmd.Stdout = out
+	pagerCmd.Stderr = os.Stderr
+	return pagerCmd.Run()
+}
+
+func NewCmdDiff(f *cmdutil.Factory, runF func(*DiffOptions) error) *cobra.Command {
+	opts := &DiffOptions{
+		IO:         f.IOStreams,
+		HttpClient: f.HttpClient,
+		BaseRepo:   f.BaseRepo,
+		Branch:     f.Branch,
+		Remotes:    f.Remotes,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "diff [<number> | <url> | <branch>]",
+		Short: "View changes in a pull request",
+		Long: heredoc.Doc(`
+			View changes in a pull request.
+
+			Without an argument, the pull request that belongs to the current branch
+			is selected.
+		`),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				opts.SelectorArg = args[0]
+			}
+
+			if !validColorFlag(opts.UseColor) {
+				return &cmdutil.FlagError{Err: fmt.Errorf("did not understand color: %q. Expected one of always, never, or auto", opts.UseColor)}
+			}
+
+			if opts.UseColor == "auto" && !opts.IO.IsStdoutTTY() {
+				opts.UseColor = "never"
+			}
+
+			if opts.Local && opts.SelectorArg != "" {
+				return &cmdutil.FlagError{Err: fmt.Errorf("cannot use a pull request selector together with --local")}
+			}
+
+			if !validStyleFlag(opts.Style) {
+				return &cmdutil.FlagError{Err: fmt.Errorf("did not understand style: %q. Expected one of unified, side-by-side, or word", opts.Style)}
+			}
+
+			if !validHighlightFlag(opts.Highlight) {
+				return &cmdutil.FlagError{Err: fmt.Errorf("did not understand highlight: %q. Expected one of auto, never, or always", opts.Highlight)}
+			}
+
+			if !validFormatFlag(opts.Format) {
+				return &cmdutil.FlagError{Err: fmt.Errorf("did not understand format: %q. Expected one of patch, mbox, or sarif", opts.Format)}
+			}
+
+			if !validApplyFlag(opts.Apply) {
+				return &cmdutil.FlagError{Err: fmt.Errorf("did not understand apply: %q. Expected one of \"\", direct, or 3way", opts.Apply)}
+			}
+
+			if opts.Format != "" && opts.Apply != "" {
+				return &cmdutil.FlagError{Err: fmt.Errorf("cannot use --format together with --apply")}
+			}
+
+			if opts.OnlyAdded && opts.OnlyModified {
+				return &cmdutil.FlagError{Err: fmt.Errorf("cannot use --only-added together with --only-modified")}
+			}
+
+			if opts.Exporter != nil && opts.Format != "" {
+				return &cmdutil.FlagError{Err: fmt.Errorf("cannot use --json together with --format")}
+			}
+
+			if opts.Exporter != nil && opts.Apply != "" {
+				return &cmdutil.FlagError{Err: fmt.Errorf("cannot use --json together with --apply")}
+			}
+
+			if runF != nil {
+				return runF(opts)
+			}
+			return diffRun(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.UseColor, "color", "auto", "Use color in diff output: {always|never|auto}")
+	cmd.Flags().BoolVar(&opts.Local, "local", false, "Compute the diff locally using git instead of fetching it from GitHub")
+	cmd.Flags().StringVar(&opts.BaseBranch, "base", "", "Base ref to diff against when using --local (defaults to the branch's recorded PR base, else the default branch)")
+	cmd.Flags().StringVar(&opts.HeadBranch, "head", "", "Head ref to diff when using --local (defaults to the current branch)")
+	cmd.Flags().StringVar(&opts.Style, "style", defaultDiffStyle, "Diff rendering style: {unified|side-by-side|word}")
+	cmd.Flags().IntVar(&opts.Context, "context", -1, "Number of context lines to show around each change")
+	cmd.Flags().StringVar(&opts.Highlight, "highlight", highlightAuto, "Syntax-highlight hunk content: {auto|never|always}")
+	cmd.Flags().StringVar(&opts.Theme, "theme", "", "Chroma theme to use for syntax highlighting (defaults based on TTY)")
+	cmd.Flags().StringVar(&opts.Format, "format", "", "Emit the diff as a format-patch-style message series or a SARIF report instead of a plain diff: {patch|mbox|sarif}")
+	cmd.Flags().StringVar(&opts.Apply, "apply", "", "Apply the diff to the working tree instead of printing it; pass --apply=3way for a 3-way merge")
+	cmd.Flags().Lookup("apply").NoOptDefVal = applyDirect
+	cmd.Flags().StringArrayVar(&opts.Paths, "path", nil, "Only show files matching this glob, evaluated against their new path (can be given multiple times)")
+	cmd.Flags().StringArrayVar(&opts.Excludes, "exclude", nil, "Hide files matching this glob, evaluated against their new path (can be given multiple times)")
+	cmd.Flags().BoolVar(&opts.OnlyAdded, "only-added", false, "Only show added files")
+	cmd.Flags().BoolVar(&opts.OnlyModified, "only-modified", false, "Only show modified files")
+	cmd.Flags().BoolVar(&opts.Stat, "stat", false, "Show a diffstat-style summary instead of the diff")
+	cmdutil.AddJSONFlags(cmd, &opts.Exporter, jsonFields)
+
+	return cmd
+}
+
+func diffRun(opts *DiffOptions) error {
+	if opts.Exporter != nil {
+		return runJSON(opts)
+	}
+
+	if opts.Format == formatSarif {
+		return runSarif(opts)
+	}
+
+	if opts.Format != "" {
+		return runPatchSeries(opts)
+	}
+
+	diff, err := fetchDiff(opts)
+	if err != nil {
+		return err
+	}
+	defer diff.Close()
+
+	if opts.Apply != "" {
+		filtered, err := filterDiff(opts, diff)
+		if err != nil {
+			return err
+		}
+		return applyPatch(filtered, opts.Apply == apply3Way)
+	}
+
+	filtered, err := filterAndSummarizeDiff(opts, diff)
+	if err != nil {
+		return err
+	}
+
+	if opts.Stat {
+		_, err := io.Copy(opts.IO.Out, filtered)
+		return err
+	}
+
+	return renderDiff(opts, filtered)
+}
+
+// fetchDiff retrieves the raw unified diff to operate on, either from the
+// local git repository or from the API, depending on opts.Local.
+func fetchDiff(opts *DiffOptions) (io.ReadCloser, error) {
+	if opts.Local {
+		return localDiff(opts)
+	}
+
+	httpClient, err := opts.HttpClient()
+	if err != nil {
+		return nil, err
+	}
+	apiClient := api.NewClientFromHTTP(httpClient)
+
+	baseRepo, err := opts.BaseRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := shared.PRFromArgs(apiClient, baseRepo, opts.Branch, opts.Remotes, opts.SelectorArg)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := apiClient.PullRequestDiff(baseRepo, pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("could not find pull request diff: %w", err)
+	}
+	return diff, nil
+}
+
+// localRefs resolves the base and head refs to diff between from opts,
+// defaulting head to the current branch. The base ref comes from --base if
+// given, or from the "gh-merge-base" git config `gh pr checkout` records
+// for the branch when it has one, and only falls back to guessing the
+// repository's default branch when neither is available.
+func localRefs(opts *DiffOptions) (base, head string, err error) {
+	head = opts.HeadBranch
+	if head == "" {
+		head, err = opts.Branch()
+		if err != nil {
+			return "", "", fmt.Errorf("could not determine the current branch: %w", err)
+		}
+	}
+
+	base = opts.BaseBranch
+	if base == "" {
+		base, err = prBaseFromGitConfig(head)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if base == "" {
+		base, err = git.DefaultBranch()
+		if err != nil {
+			return "", "", fmt.Errorf("could not determine a base branch; pass --base explicitly: %w", err)
+		}
+	}
+
+	return base, head, nil
+}
+
+// prBaseFromGitConfig reads the "gh-merge-base" git config `gh pr checkout`
+// records for branch, so --local can target the PR's actual base instead of
+// guessing the repository's default branch, which is wrong for release
+// branches and stacked PRs. It returns "" (not an error) when branch has no
+// recorded base, e.g. because it wasn't checked out with `gh pr checkout`.
+func prBaseFromGitConfig(branch string) (string, error) {
+	out, err := exec.Command("git", "config", "--get", "branch."+branch+".gh-merge-base").Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", nil
+		}
+		return "", fmt.Errorf("could not read local branch config: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// localDiff resolves base and head refs from the local git repository and
+// returns the diff between them without making any network requests.
+func localDiff(opts *DiffOptions) (io.ReadCloser, error) {
+	base, head, err := localRefs(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := git.Diff(base, head)
+	if err != nil {
+		return nil, fmt.Errorf("could not compute local diff between %q and %q: %w", base, head, err)
+	}
+
+	return diff, nil
+}
+
+// renderDiff writes diff to opts.IO.Out, applying colorization, styling, and
+// paging as appropriate for the current options and TTY state. It is shared
+// by both the API-backed and --local code paths. Paging always wraps the
+// fully rendered output rather than the raw diff, so a pager doesn't bypass
+// --style, --context, or --highlight.
+func renderDiff(opts *DiffOptions, diff io.Reader) error {
+	if opts.UseColor == "never" && opts.Style == styleUnified && opts.Context < 0 {
+		return pageOrCopy(opts, diff)
+	}
+
+	// "auto" highlights only on a TTY with 256-color support, which the
+	// chroma TTY256 formatter's output needs.
+	var hl *highlighter
+	if opts.UseColor != "never" && (opts.Highlight == highlightAlways ||
+		(opts.Highlight == highlightAuto && opts.IO.IsStdoutTTY() && opts.IO.ColorSupport256())) {
+		hl = newHighlighter(resolveTheme(opts.Theme, opts.IO.IsStdoutTTY()))
+	}
+
+	var buf bytes.Buffer
+	if opts.Style != styleUnified || opts.Context >= 0 {
+		if err := renderStyledDiff(&buf, opts, diff, hl); err != nil {
+			return err
+		}
+	} else if err := colorDiffLinesHighlighted(&buf, diff, hl); err != nil {
+		return err
+	}
+
+	return pageOrCopy(opts, &buf)
+}
+
+// pageOrCopy writes rendered to opts.IO.Out, piping it through $PAGER when
+// stdout is a TTY and a pager is configured.
+func pageOrCopy(opts *DiffOptions, rendered io.Reader) error {
+	if opts.IO.IsStdoutTTY() {
+		if pager := os.Getenv("PAGER"); pager != "" {
+			return runPager(pager, rendered, opts.IO.Out)
+		}
+	}
+	_, err := io.Copy(opts.IO.Out, rendered)
+	return err
+}
+
+func isDiffHeaderLine(line string) bool {
+	for _, prefix := range []string{"diff --git", "index ", "--- ", "+++ "} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func validColorFlag(c string) bool {
+	return c == "always" || c == "never" || c == "auto"
+}
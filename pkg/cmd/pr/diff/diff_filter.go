@@ -0,0 +1,274 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	statusAdded    = "added"
+	statusDeleted  = "removed"
+	statusRenamed  = "renamed"
+	statusModified = "modified"
+)
+
+// fileDiffStatus classifies a fileDiff by its header lines.
+func fileDiffStatus(f *fileDiff) string {
+	for _, line := range f.Header {
+		switch {
+		case strings.HasPrefix(line, "new file mode"):
+			return statusAdded
+		case strings.HasPrefix(line, "deleted file mode"):
+			return statusDeleted
+		case strings.HasPrefix(line, "rename from "):
+			return statusRenamed
+		}
+	}
+	return statusModified
+}
+
+// diffFilter narrows a parsed diff down to the files requested via --path,
+// --exclude, --only-added, and --only-modified.
+type diffFilter struct {
+	Paths        []string
+	Excludes     []string
+	OnlyAdded    bool
+	OnlyModified bool
+}
+
+func newDiffFilter(opts *DiffOptions) diffFilter {
+	return diffFilter{
+		Paths:        opts.Paths,
+		Excludes:     opts.Excludes,
+		OnlyAdded:    opts.OnlyAdded,
+		OnlyModified: opts.OnlyModified,
+	}
+}
+
+func (f diffFilter) isEmpty() bool {
+	return len(f.Paths) == 0 && len(f.Excludes) == 0 && !f.OnlyAdded && !f.OnlyModified
+}
+
+// matches reports whether fd should be kept under f.
+func (f diffFilter) matches(fd *fileDiff) (bool, error) {
+	path := fileDiffPath(fd)
+
+	if len(f.Paths) > 0 {
+		var matched bool
+		for _, pattern := range f.Paths {
+			ok, err := doublestar.Match(pattern, path)
+			if err != nil {
+				return false, fmt.Errorf("invalid --path glob %q: %w", pattern, err)
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	for _, pattern := range f.Excludes {
+		ok, err := doublestar.Match(pattern, path)
+		if err != nil {
+			return false, fmt.Errorf("invalid --exclude glob %q: %w", pattern, err)
+		}
+		if ok {
+			return false, nil
+		}
+	}
+
+	status := fileDiffStatus(fd)
+	if f.OnlyAdded && status != statusAdded {
+		return false, nil
+	}
+	if f.OnlyModified && status != statusModified {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// apply returns the subset of files matching f, preserving order.
+func (f diffFilter) apply(files []*fileDiff) ([]*fileDiff, error) {
+	if f.isEmpty() {
+		return files, nil
+	}
+	var out []*fileDiff
+	for _, fd := range files {
+		ok, err := f.matches(fd)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, fd)
+		}
+	}
+	return out, nil
+}
+
+// filterAndSummarizeDiff filters diff by opts' --path/--exclude/--only-*
+// flags and, if opts.Stat is set, replaces it with a diffstat-style summary.
+// When no filtering or stat flags are set it returns diff unchanged so the
+// common case avoids the parse/re-serialize round trip.
+func filterAndSummarizeDiff(opts *DiffOptions, diff io.Reader) (io.Reader, error) {
+	filter := newDiffFilter(opts)
+	if filter.isEmpty() && !opts.Stat {
+		return diff, nil
+	}
+
+	files, err := parseUnifiedDiff(diff)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err = filter.apply(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if opts.Stat {
+		writeDiffStat(&buf, files, opts.IO.TerminalWidth())
+	} else if err := writeUnifiedDiff(&buf, files); err != nil {
+		return nil, err
+	}
+
+	return &buf, nil
+}
+
+// filterDiff applies the usual --path/--exclude/--only-* filters to diff and
+// re-serializes the result as plain unified diff text, for callers (like
+// --apply) that need a filtered diff rather than a rendered or summarized
+// one. It returns diff unchanged when no filter flags are set.
+func filterDiff(opts *DiffOptions, diff io.Reader) (io.Reader, error) {
+	filter := newDiffFilter(opts)
+	if filter.isEmpty() {
+		return diff, nil
+	}
+
+	files, err := parseUnifiedDiff(diff)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err = filter.apply(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeUnifiedDiff(&buf, files); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// writeUnifiedDiff re-serializes files back into raw unified diff text so
+// the rest of the rendering pipeline can keep operating on plain text.
+func writeUnifiedDiff(w io.Writer, files []*fileDiff) error {
+	for _, f := range files {
+		for _, line := range f.Header {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		for _, h := range f.Hunks {
+			if _, err := fmt.Fprintln(w, hunkHeaderText(h)); err != nil {
+				return err
+			}
+			for _, l := range h.Lines {
+				if _, err := fmt.Fprintf(w, "%c%s\n", l.Kind, l.Text); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fileStatTotal holds a file's line-change counts for --stat.
+type fileStatTotal struct {
+	Path    string
+	Added   int
+	Removed int
+}
+
+func statTotals(files []*fileDiff) []fileStatTotal {
+	totals := make([]fileStatTotal, 0, len(files))
+	for _, f := range files {
+		t := fileStatTotal{Path: fileDiffPath(f)}
+		for _, h := range f.Hunks {
+			for _, l := range h.Lines {
+				switch l.Kind {
+				case '+':
+					t.Added++
+				case '-':
+					t.Removed++
+				}
+			}
+		}
+		totals = append(totals, t)
+	}
+	return totals
+}
+
+// writeDiffStat renders a `git diff --stat`-style summary: one line per
+// file with a bar chart scaled to fit termWidth, followed by a totals line.
+func writeDiffStat(w io.Writer, files []*fileDiff, termWidth int) {
+	totals := statTotals(files)
+
+	maxNameLen, maxChanges := 0, 0
+	for _, t := range totals {
+		if len(t.Path) > maxNameLen {
+			maxNameLen = len(t.Path)
+		}
+		if c := t.Added + t.Removed; c > maxChanges {
+			maxChanges = c
+		}
+	}
+
+	if termWidth <= 0 {
+		termWidth = 80
+	}
+	// Leave room for "<name> | <count> ".
+	barWidth := termWidth - maxNameLen - 10
+	if barWidth < 1 {
+		barWidth = 1
+	}
+
+	var filesChanged, totalAdded, totalRemoved int
+	for _, t := range totals {
+		filesChanged++
+		totalAdded += t.Added
+		totalRemoved += t.Removed
+
+		changes := t.Added + t.Removed
+		plus, minus := 0, 0
+		if maxChanges > 0 {
+			scaled := changes * barWidth / maxChanges
+			if scaled < 1 && changes > 0 {
+				scaled = 1
+			}
+			if changes > 0 {
+				plus = scaled * t.Added / changes
+				minus = scaled - plus
+			}
+		}
+
+		fmt.Fprintf(w, " %-*s | %d %s%s\n", maxNameLen, t.Path, changes,
+			strings.Repeat("+", plus), strings.Repeat("-", minus))
+	}
+
+	fmt.Fprintf(w, " %d file", filesChanged)
+	if filesChanged != 1 {
+		fmt.Fprint(w, "s")
+	}
+	fmt.Fprintf(w, " changed, +%d/-%d\n", totalAdded, totalRemoved)
+}
@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffFilter_apply_path(t *testing.T) {
+	files, err := parseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	filter := diffFilter{Paths: []string{"Makefile"}}
+	got, err := filter.apply(files)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(got))
+	}
+	if path := fileDiffPath(got[0]); path != "Makefile" {
+		t.Errorf("expected Makefile, got %q", path)
+	}
+}
+
+func TestDiffFilter_apply_exclude(t *testing.T) {
+	files, err := parseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	filter := diffFilter{Excludes: []string{"**/*.yml"}}
+	got, err := filter.apply(files)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(got))
+	}
+	if path := fileDiffPath(got[0]); path != "Makefile" {
+		t.Errorf("expected Makefile, got %q", path)
+	}
+}
+
+func TestDiffFilter_onlyAdded(t *testing.T) {
+	files, err := parseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	filter := diffFilter{OnlyAdded: true}
+	got, err := filter.apply(files)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected no added files in testDiff, got %d", len(got))
+	}
+}
+
+func TestStatTotals(t *testing.T) {
+	files, err := parseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	totals := statTotals(files)
+	if len(totals) != 2 {
+		t.Fatalf("expected 2 totals, got %d", len(totals))
+	}
+	if totals[0].Added != 5 || totals[0].Removed != 0 {
+		t.Errorf("expected +5/-0 for %s, got +%d/-%d", totals[0].Path, totals[0].Added, totals[0].Removed)
+	}
+	if totals[1].Added != 2 || totals[1].Removed != 2 {
+		t.Errorf("expected +2/-2 for %s, got +%d/-%d", totals[1].Path, totals[1].Added, totals[1].Removed)
+	}
+}
+
+func TestWriteDiffStat(t *testing.T) {
+	files, err := parseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	writeDiffStat(&buf, files, 80)
+
+	out := buf.String()
+	if !strings.Contains(out, "2 files changed, +7/-2") {
+		t.Errorf("expected totals line, got:\n%s", out)
+	}
+}
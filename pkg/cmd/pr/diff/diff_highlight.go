@@ -0,0 +1,151 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+const (
+	highlightAuto   = "auto"
+	highlightNever  = "never"
+	highlightAlways = "always"
+
+	darkTheme  = "dracula"
+	lightTheme = "monokai"
+)
+
+func validHighlightFlag(h string) bool {
+	switch h {
+	case highlightAuto, highlightNever, highlightAlways:
+		return true
+	}
+	return false
+}
+
+var diffGitPathRe = regexp.MustCompile(`^diff --git a/.+ b/(.+)$`)
+
+func parseDiffGitPath(line string) string {
+	if m := diffGitPathRe.FindStringSubmatch(line); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// highlighter applies chroma syntax highlighting to diff content lines,
+// caching the lexer lookup for each file seen within one invocation.
+type highlighter struct {
+	theme  string
+	lexers map[string]chroma.Lexer
+}
+
+func newHighlighter(theme string) *highlighter {
+	return &highlighter{theme: theme, lexers: map[string]chroma.Lexer{}}
+}
+
+func (h *highlighter) lexerFor(filename string) chroma.Lexer {
+	if l, ok := h.lexers[filename]; ok {
+		return l
+	}
+	l := lexers.Match(filename)
+	if l == nil {
+		l = lexers.Fallback
+	}
+	l = chroma.Coalesce(l)
+	h.lexers[filename] = l
+	return l
+}
+
+// highlight tokenizes a single line of code as filename's language and
+// renders it with ANSI color, preserving the raw text (without its leading
+// diff marker) so callers can layer their own +/- coloring around it.
+func (h *highlighter) highlight(filename, code string) (string, error) {
+	lexer := h.lexerFor(filename)
+
+	style := styles.Get(h.theme)
+	if style == nil {
+		style = styles.Fallback
+	}
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return code, err
+	}
+
+	var buf bytes.Buffer
+	if err := formatters.TTY256.Format(&buf, style, iterator); err != nil {
+		return code, err
+	}
+
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// resolveTheme picks a dark-background theme for TTY output and a more
+// neutral one otherwise, unless the user passed an explicit --theme.
+func resolveTheme(explicit string, isTTY bool) string {
+	if explicit != "" {
+		return explicit
+	}
+	if isTTY {
+		return darkTheme
+	}
+	return lightTheme
+}
+
+// highlightLine returns text highlighted for path, or text unchanged if hl
+// is nil or highlighting fails.
+func highlightLine(hl *highlighter, path, text string) string {
+	if hl == nil {
+		return text
+	}
+	highlighted, err := hl.highlight(path, text)
+	if err != nil {
+		return text
+	}
+	return highlighted
+}
+
+// colorDiffLinesHighlighted is the default unified renderer: it colors
+// header, "@@" and +/- lines and, when hl is non-nil, tokenizes +/- line
+// content with chroma, leaving only the marker in the usual diff-line color.
+func colorDiffLinesHighlighted(w io.Writer, r io.Reader, hl *highlighter) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var path string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case isDiffHeaderLine(line):
+			if p := parseDiffGitPath(line); p != "" {
+				path = p
+			}
+			fmt.Fprintf(w, "\x1b[1m%s\x1b[m\n", line)
+		case strings.HasPrefix(line, "@@"):
+			fmt.Fprintf(w, "\x1b[36m%s\x1b[m\n", line)
+		case strings.HasPrefix(line, "+"):
+			writeHighlightedPrefixedLine(w, '+', "\x1b[32m", line[1:], path, hl)
+		case strings.HasPrefix(line, "-"):
+			writeHighlightedPrefixedLine(w, '-', "\x1b[31m", line[1:], path, hl)
+		default:
+			fmt.Fprintln(w, line)
+		}
+	}
+	return scanner.Err()
+}
+
+func writeHighlightedPrefixedLine(w io.Writer, marker byte, markerColor, text, path string, hl *highlighter) {
+	if hl == nil {
+		fmt.Fprintf(w, "%s%c%s\x1b[m\n", markerColor, marker, text)
+		return
+	}
+	fmt.Fprintf(w, "%s%c\x1b[m%s\n", markerColor, marker, highlightLine(hl, path, text))
+}
@@ -0,0 +1,25 @@
+package diff
+
+import "testing"
+
+func TestResolveTheme(t *testing.T) {
+	if got := resolveTheme("solarized-dark", true); got != "solarized-dark" {
+		t.Errorf("expected explicit theme to win, got %q", got)
+	}
+	if got := resolveTheme("", true); got != darkTheme {
+		t.Errorf("expected %q for TTY, got %q", darkTheme, got)
+	}
+	if got := resolveTheme("", false); got != lightTheme {
+		t.Errorf("expected %q for non-TTY, got %q", lightTheme, got)
+	}
+}
+
+func TestParseDiffGitPath(t *testing.T) {
+	got := parseDiffGitPath("diff --git a/pkg/cmd/pr/diff/diff.go b/pkg/cmd/pr/diff/diff.go")
+	if want := "pkg/cmd/pr/diff/diff.go"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := parseDiffGitPath("@@ -1,2 +1,2 @@"); got != "" {
+		t.Errorf("expected empty path for non-header line, got %q", got)
+	}
+}
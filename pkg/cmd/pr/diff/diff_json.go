@@ -0,0 +1,278 @@
+package diff
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const formatSarif = "sarif"
+
+var jsonFields = []string{"path", "oldPath", "status", "oldMode", "newMode", "additions", "deletions", "hunks"}
+
+// jsonFileDiff is the --json representation of one file's changes.
+type jsonFileDiff struct {
+	Path      string     `json:"path"`
+	OldPath   string     `json:"oldPath,omitempty"`
+	Status    string     `json:"status"`
+	OldMode   string     `json:"oldMode,omitempty"`
+	NewMode   string     `json:"newMode,omitempty"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+	Hunks     []jsonHunk `json:"hunks"`
+}
+
+// ExportData implements cmdutil.Exporter's per-item field selection for
+// --json=<fields>.
+func (f jsonFileDiff) ExportData(fields []string) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "path":
+			m["path"] = f.Path
+		case "oldPath":
+			m["oldPath"] = f.OldPath
+		case "status":
+			m["status"] = f.Status
+		case "oldMode":
+			m["oldMode"] = f.OldMode
+		case "newMode":
+			m["newMode"] = f.NewMode
+		case "additions":
+			m["additions"] = f.Additions
+		case "deletions":
+			m["deletions"] = f.Deletions
+		case "hunks":
+			m["hunks"] = f.Hunks
+		}
+	}
+	return m
+}
+
+type jsonHunk struct {
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Lines    []jsonLine `json:"lines"`
+}
+
+// jsonLine tags a hunk line as "context", "add", or "del".
+type jsonLine struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+var indexModeRe = regexp.MustCompile(`^index [0-9a-f]+\.\.[0-9a-f]+ (\d+)$`)
+
+// buildJSONFiles converts parsed fileDiffs into their --json representation.
+func buildJSONFiles(files []*fileDiff) []jsonFileDiff {
+	out := make([]jsonFileDiff, 0, len(files))
+	for _, f := range files {
+		status := fileDiffStatus(f)
+		jf := jsonFileDiff{
+			Path:   fileDiffPath(f),
+			Status: status,
+		}
+
+		for _, line := range f.Header {
+			switch {
+			case strings.HasPrefix(line, "rename from "):
+				jf.OldPath = strings.TrimPrefix(line, "rename from ")
+			case strings.HasPrefix(line, "old mode "):
+				jf.OldMode = strings.TrimPrefix(line, "old mode ")
+			case strings.HasPrefix(line, "new mode "):
+				jf.NewMode = strings.TrimPrefix(line, "new mode ")
+			case strings.HasPrefix(line, "new file mode "):
+				jf.NewMode = strings.TrimPrefix(line, "new file mode ")
+			case strings.HasPrefix(line, "deleted file mode "):
+				jf.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+			default:
+				if m := indexModeRe.FindStringSubmatch(line); m != nil {
+					// A bare "index <old>..<new> <mode>" line only carries a
+					// mode shared by both sides for a plain content change;
+					// added/deleted files have no "old"/"new" side to report
+					// a mode for, even though the line still matches.
+					if status != statusAdded {
+						jf.OldMode = m[1]
+					}
+					if status != statusDeleted {
+						jf.NewMode = m[1]
+					}
+				}
+			}
+		}
+
+		for _, h := range f.Hunks {
+			jh := jsonHunk{OldStart: h.OldStart, OldLines: h.OldLines, NewStart: h.NewStart, NewLines: h.NewLines}
+			for _, l := range h.Lines {
+				kind := "context"
+				switch l.Kind {
+				case '+':
+					kind = "add"
+					jf.Additions++
+				case '-':
+					kind = "del"
+					jf.Deletions++
+				}
+				jh.Lines = append(jh.Lines, jsonLine{Type: kind, Text: l.Text})
+			}
+			jf.Hunks = append(jf.Hunks, jh)
+		}
+
+		out = append(out, jf)
+	}
+	return out
+}
+
+// runJSON parses the PR diff, applies the usual --path/--exclude/--only-*
+// filters, and exports the result through opts.Exporter.
+func runJSON(opts *DiffOptions) error {
+	diff, err := fetchDiff(opts)
+	if err != nil {
+		return err
+	}
+	defer diff.Close()
+
+	files, err := parseUnifiedDiff(diff)
+	if err != nil {
+		return err
+	}
+
+	files, err = newDiffFilter(opts).apply(files)
+	if err != nil {
+		return err
+	}
+
+	jsonFiles := buildJSONFiles(files)
+	data := make([]interface{}, len(jsonFiles))
+	for i, jf := range jsonFiles {
+		data[i] = jf
+	}
+
+	return opts.Exporter.Export(opts.IO, data)
+}
+
+// sarifLog is a minimal SARIF v2.1.0 document: one result per changed line.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string        `json:"name"`
+	Rules []interface{} `json:"rules"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// buildSarif turns files into a SARIF log with one result per added or
+// removed line, so CI linters can consume the PR's changed-line surface.
+func buildSarif(files []*fileDiff) sarifLog {
+	log := sarifLog{
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{Name: "gh-pr-diff", Rules: []interface{}{}}},
+		}},
+	}
+
+	run := &log.Runs[0]
+	for _, f := range files {
+		path := fileDiffPath(f)
+		for _, h := range f.Hunks {
+			oldLine, newLine := h.OldStart, h.NewStart
+			for _, l := range h.Lines {
+				switch l.Kind {
+				case '+':
+					run.Results = append(run.Results, sarifResult{
+						RuleID:  "added-line",
+						Level:   "note",
+						Message: sarifMessage{Text: l.Text},
+						Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifactLocation{URI: path},
+							Region:           sarifRegion{StartLine: newLine},
+						}}},
+					})
+					newLine++
+				case '-':
+					run.Results = append(run.Results, sarifResult{
+						RuleID:  "removed-line",
+						Level:   "note",
+						Message: sarifMessage{Text: l.Text},
+						Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifactLocation{URI: path},
+							Region:           sarifRegion{StartLine: oldLine},
+						}}},
+					})
+					oldLine++
+				default:
+					oldLine++
+					newLine++
+				}
+			}
+		}
+	}
+
+	return log
+}
+
+// runSarif parses the PR diff, applies the usual filters, and writes it to
+// opts.IO.Out as a SARIF document.
+func runSarif(opts *DiffOptions) error {
+	diff, err := fetchDiff(opts)
+	if err != nil {
+		return err
+	}
+	defer diff.Close()
+
+	files, err := parseUnifiedDiff(diff)
+	if err != nil {
+		return err
+	}
+
+	files, err = newDiffFilter(opts).apply(files)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(opts.IO.Out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(buildSarif(files))
+}
@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildJSONFiles_golden(t *testing.T) {
+	files, err := parseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got, err := json.MarshalIndent(buildJSONFiles(files), "", "  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want, err := ioutil.ReadFile("testdata/diff.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if diff := cmp.Diff(strings.TrimRight(string(want), "\n"), string(got)); diff != "" {
+		t.Errorf("--json output did not match testdata/diff.json:\n%s", diff)
+	}
+}
+
+func TestBuildSarif(t *testing.T) {
+	files, err := parseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	log := buildSarif(files)
+	if log.Version != "2.1.0" {
+		t.Errorf("expected SARIF version 2.1.0, got %q", log.Version)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("expected 1 run, got %d", len(log.Runs))
+	}
+
+	var added, removed int
+	for _, r := range log.Runs[0].Results {
+		switch r.RuleID {
+		case "added-line":
+			added++
+		case "removed-line":
+			removed++
+		}
+	}
+	if added != 5 {
+		t.Errorf("expected 5 added-line results, got %d", added)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 removed-line results, got %d", removed)
+	}
+}
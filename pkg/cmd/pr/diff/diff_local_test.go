@@ -0,0 +1,15 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestPRDiff_localAndSelectorConflict(t *testing.T) {
+	_, err := runCommand(nil, nil, false, "--local 123")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if got := err.Error(); got != "cannot use a pull request selector together with --local" {
+		t.Errorf("unexpected error message: %s", got)
+	}
+}
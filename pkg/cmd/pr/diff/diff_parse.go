@@ -0,0 +1,117 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fileDiff is a single file's section of a unified diff: its raw header
+// lines (the "diff --git", "index", "---", "+++" lines) plus its hunks.
+type fileDiff struct {
+	Header []string
+	Hunks  []*diffHunk
+}
+
+// diffHunk is one "@@ ... @@" section of a fileDiff. Heading is the
+// optional function/section context git appends after the second "@@".
+type diffHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Heading            string
+	Lines              []diffLine
+}
+
+// diffLine is a single line of a hunk body. Kind is one of ' ', '+', or '-'.
+type diffLine struct {
+	Kind byte
+	Text string
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// parseUnifiedDiff splits a unified diff into per-file sections and hunks so
+// that renderers other than the plain line colorizer can operate on its
+// structure instead of raw text.
+func parseUnifiedDiff(r io.Reader) ([]*fileDiff, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var files []*fileDiff
+	var curFile *fileDiff
+	var curHunk *diffHunk
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git"):
+			curFile = &fileDiff{Header: []string{line}}
+			curHunk = nil
+			files = append(files, curFile)
+		case curFile != nil && hunkHeaderRe.MatchString(line):
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			curHunk = &diffHunk{
+				OldStart: atoiOr(m[1], 0),
+				OldLines: atoiOr(m[2], 1),
+				NewStart: atoiOr(m[3], 0),
+				NewLines: atoiOr(m[4], 1),
+				Heading:  m[5],
+			}
+			curFile.Hunks = append(curFile.Hunks, curHunk)
+		case curHunk != nil && len(line) > 0 && (line[0] == '+' || line[0] == '-' || line[0] == ' '):
+			curHunk.Lines = append(curHunk.Lines, diffLine{Kind: line[0], Text: line[1:]})
+		case curHunk == nil && curFile != nil:
+			curFile.Header = append(curFile.Header, line)
+		default:
+			// Line outside of any file section (e.g. leading blank lines);
+			// ignore it rather than failing the whole parse.
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not parse diff: %w", err)
+	}
+
+	return files, nil
+}
+
+func atoiOr(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// fileDiffPath returns the "b/"-relative path a fileDiff's header refers to,
+// falling back to the "a/" path for deletions and to the "rename to "/
+// "rename from " lines for a pure rename, which has neither a "+++" nor a
+// "---" line.
+func fileDiffPath(f *fileDiff) string {
+	for _, line := range f.Header {
+		if strings.HasPrefix(line, "+++ b/") {
+			return strings.TrimPrefix(line, "+++ b/")
+		}
+	}
+	for _, line := range f.Header {
+		if strings.HasPrefix(line, "--- a/") {
+			return strings.TrimPrefix(line, "--- a/")
+		}
+	}
+	for _, line := range f.Header {
+		if strings.HasPrefix(line, "rename to ") {
+			return strings.TrimPrefix(line, "rename to ")
+		}
+	}
+	for _, line := range f.Header {
+		if strings.HasPrefix(line, "rename from ") {
+			return strings.TrimPrefix(line, "rename from ")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,276 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cli/cli/api"
+	"github.com/cli/cli/git"
+	"github.com/cli/cli/internal/ghrepo"
+	"github.com/cli/cli/pkg/cmd/pr/shared"
+)
+
+const (
+	formatPatch = "patch"
+	formatMbox  = "mbox"
+
+	applyDirect = "direct"
+	apply3Way   = "3way"
+)
+
+func validFormatFlag(f string) bool {
+	switch f {
+	case "", formatPatch, formatMbox, formatSarif:
+		return true
+	}
+	return false
+}
+
+func validApplyFlag(a string) bool {
+	switch a {
+	case "", applyDirect, apply3Way:
+		return true
+	}
+	return false
+}
+
+// patchCommit holds one commit's metadata and diff, enough to render a
+// single format-patch-style message.
+type patchCommit struct {
+	SHA     string
+	Subject string
+	Body    string
+	Author  string
+	Email   string
+	Date    time.Time
+	Diff    string
+}
+
+// runPatchSeries resolves the PR's commits, one way or another, and writes
+// them to opts.IO.Out as a format-patch-style message series suitable for
+// `git am`.
+func runPatchSeries(opts *DiffOptions) error {
+	var commits []patchCommit
+
+	if opts.Local {
+		base, head, err := localRefs(opts)
+		if err != nil {
+			return err
+		}
+		commits, err = localCommits(base, head)
+		if err != nil {
+			return err
+		}
+	} else {
+		httpClient, err := opts.HttpClient()
+		if err != nil {
+			return err
+		}
+		apiClient := api.NewClientFromHTTP(httpClient)
+
+		baseRepo, err := opts.BaseRepo()
+		if err != nil {
+			return err
+		}
+
+		pr, err := shared.PRFromArgs(apiClient, baseRepo, opts.Branch, opts.Remotes, opts.SelectorArg)
+		if err != nil {
+			return err
+		}
+
+		commits, err = apiCommits(apiClient, baseRepo, pr.Number)
+		if err != nil {
+			return err
+		}
+	}
+
+	commits, err := filterPatchCommits(opts, commits)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(opts.IO.Out, formatPatchSeries(commits, opts.Format == formatMbox))
+	return err
+}
+
+// filterPatchCommits applies the usual --path/--exclude/--only-* filters to
+// each commit's diff, dropping commits left with no matching files so
+// `--format` respects the same filtering as every other diff output.
+func filterPatchCommits(opts *DiffOptions, commits []patchCommit) ([]patchCommit, error) {
+	filter := newDiffFilter(opts)
+	if filter.isEmpty() {
+		return commits, nil
+	}
+
+	filtered := make([]patchCommit, 0, len(commits))
+	for _, c := range commits {
+		files, err := parseUnifiedDiff(strings.NewReader(c.Diff))
+		if err != nil {
+			return nil, err
+		}
+		files, err = filter.apply(files)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := writeUnifiedDiff(&buf, files); err != nil {
+			return nil, err
+		}
+		c.Diff = buf.String()
+		filtered = append(filtered, c)
+	}
+	return filtered, nil
+}
+
+// formatPatchSeries renders commits as `git format-patch`-style messages,
+// each carrying a "[PATCH n/m]" subject line. When mbox is true, each
+// message is preceded by the "From <sha> <date>" line `git am` expects when
+// a series is piped to it as a single stream; individual .patch files
+// produced by `git format-patch` omit that line, so "patch" format leaves
+// it off.
+func formatPatchSeries(commits []patchCommit, mbox bool) string {
+	var sb strings.Builder
+	total := len(commits)
+	for i, c := range commits {
+		if mbox {
+			fmt.Fprintf(&sb, "From %s Mon Sep 17 00:00:00 2001\n", c.SHA)
+		}
+		fmt.Fprintf(&sb, "From: %s <%s>\n", c.Author, c.Email)
+		fmt.Fprintf(&sb, "Date: %s\n", c.Date.Format("Mon, 2 Jan 2006 15:04:05 -0700"))
+		fmt.Fprintf(&sb, "Subject: [PATCH %d/%d] %s\n\n", i+1, total, c.Subject)
+
+		if c.Body != "" {
+			sb.WriteString(c.Body)
+			sb.WriteString("\n\n")
+		}
+
+		sb.WriteString("---\n")
+		sb.WriteString(c.Diff)
+		if !strings.HasSuffix(c.Diff, "\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("-- \ngh\n")
+
+		if i < total-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// localCommits lists the commits reachable from head but not base, oldest
+// first, using local git plumbing so --format works without the API.
+func localCommits(base, head string) ([]patchCommit, error) {
+	const sep, recordSep = "\x1f", "\x1e"
+	out, err := exec.Command("git", "log", "--reverse",
+		"--pretty=format:%H"+sep+"%an"+sep+"%ae"+sep+"%aI"+sep+"%s"+sep+"%b"+recordSep,
+		base+".."+head).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not list commits between %q and %q: %w", base, head, err)
+	}
+
+	var commits []patchCommit
+	for _, rec := range strings.Split(strings.Trim(string(out), recordSep+"\n"), recordSep) {
+		rec = strings.TrimPrefix(rec, "\n")
+		if rec == "" {
+			continue
+		}
+		fields := strings.SplitN(rec, sep, 6)
+		if len(fields) != 6 {
+			continue
+		}
+
+		date, _ := time.Parse(time.RFC3339, fields[3])
+
+		diff, err := git.Diff(fields[0]+"^", fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("could not diff commit %s: %w", fields[0], err)
+		}
+		diffText, err := ioutil.ReadAll(diff)
+		diff.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		commits = append(commits, patchCommit{
+			SHA:     fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    date,
+			Subject: fields[4],
+			Body:    strings.TrimSpace(fields[5]),
+			Diff:    string(diffText),
+		})
+	}
+	return commits, nil
+}
+
+// apiCommits fetches a pull request's commit list and, for each commit, its
+// individual diff, via the GraphQL and REST APIs.
+func apiCommits(apiClient *api.Client, baseRepo ghrepo.Interface, prNumber int) ([]patchCommit, error) {
+	prCommits, err := apiClient.PullRequestCommits(baseRepo, prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch pull request commits: %w", err)
+	}
+
+	commits := make([]patchCommit, 0, len(prCommits))
+	for _, c := range prCommits {
+		diff, err := apiClient.CommitDiff(baseRepo, c.OID)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch diff for commit %s: %w", c.OID, err)
+		}
+		diffText, err := ioutil.ReadAll(diff)
+		diff.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		commits = append(commits, patchCommit{
+			SHA:     c.OID,
+			Author:  c.AuthorName,
+			Email:   c.AuthorEmail,
+			Date:    c.AuthoredDate,
+			Subject: c.MessageHeadline,
+			Body:    strings.TrimSpace(c.MessageBody),
+			Diff:    string(diffText),
+		})
+	}
+	return commits, nil
+}
+
+// applyPatch pipes patch into `git apply`, optionally with a 3-way merge
+// fallback, refusing to run against a dirty working tree so a bad patch
+// doesn't get tangled up with unrelated local changes.
+func applyPatch(patch io.Reader, threeWay bool) error {
+	dirty, err := git.UncommittedChangeCount()
+	if err != nil {
+		return fmt.Errorf("could not check working tree status: %w", err)
+	}
+	if dirty > 0 {
+		return fmt.Errorf("refusing to apply: working tree has uncommitted changes")
+	}
+
+	args := []string{"apply"}
+	if threeWay {
+		args = append(args, "--3way")
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = patch
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not apply patch: %w", err)
+	}
+	return nil
+}
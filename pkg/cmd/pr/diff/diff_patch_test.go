@@ -0,0 +1,60 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCommits() []patchCommit {
+	date := time.Date(2021, time.March, 4, 12, 0, 0, 0, time.UTC)
+	return []patchCommit{
+		{SHA: "aaaa111", Author: "Jane Doe", Email: "jane@example.com", Date: date, Subject: "Add feature", Diff: "diff --git a/f b/f\n"},
+		{SHA: "bbbb222", Author: "Jane Doe", Email: "jane@example.com", Date: date, Subject: "Fix typo", Diff: "diff --git a/g b/g\n"},
+	}
+}
+
+func TestFormatPatchSeries_patch(t *testing.T) {
+	out := formatPatchSeries(testCommits(), false)
+	if strings.Contains(out, "From aaaa111 Mon Sep") {
+		t.Error("patch format should not include the mbox From-line")
+	}
+	if !strings.Contains(out, "Subject: [PATCH 1/2] Add feature") {
+		t.Errorf("expected first subject line, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Subject: [PATCH 2/2] Fix typo") {
+		t.Errorf("expected second subject line, got:\n%s", out)
+	}
+}
+
+func TestFormatPatchSeries_mbox(t *testing.T) {
+	out := formatPatchSeries(testCommits(), true)
+	if !strings.Contains(out, "From aaaa111 Mon Sep 17 00:00:00 2001") {
+		t.Errorf("expected mbox From-line for first commit, got:\n%s", out)
+	}
+	if !strings.Contains(out, "From bbbb222 Mon Sep 17 00:00:00 2001") {
+		t.Errorf("expected mbox From-line for second commit, got:\n%s", out)
+	}
+}
+
+func TestValidFormatFlag(t *testing.T) {
+	for _, v := range []string{"", formatPatch, formatMbox} {
+		if !validFormatFlag(v) {
+			t.Errorf("expected %q to be valid", v)
+		}
+	}
+	if validFormatFlag("diff") {
+		t.Error("expected \"diff\" to be invalid")
+	}
+}
+
+func TestValidApplyFlag(t *testing.T) {
+	for _, v := range []string{"", applyDirect, apply3Way} {
+		if !validApplyFlag(v) {
+			t.Errorf("expected %q to be valid", v)
+		}
+	}
+	if validApplyFlag("yes") {
+		t.Error("expected \"yes\" to be invalid")
+	}
+}
@@ -0,0 +1,400 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	styleUnified     = "unified"
+	styleSideBySide  = "side-by-side"
+	styleWord        = "word"
+	defaultDiffStyle = styleUnified
+)
+
+func validStyleFlag(s string) bool {
+	switch s {
+	case styleUnified, styleSideBySide, styleWord:
+		return true
+	}
+	return false
+}
+
+// renderStyledDiff re-parses diff into hunks and renders it according to
+// opts.Style, applying opts.Context trimming first. It is used whenever a
+// non-default style or a --context override is requested; the plain
+// colorDiffLinesHighlighted path remains the fast default for unified
+// output. hl is nil when syntax highlighting was not requested.
+func renderStyledDiff(w io.Writer, opts *DiffOptions, diff io.Reader, hl *highlighter) error {
+	files, err := parseUnifiedDiff(diff)
+	if err != nil {
+		return err
+	}
+
+	if opts.Context >= 0 {
+		for _, f := range files {
+			for _, h := range f.Hunks {
+				trimHunkContext(h, opts.Context)
+			}
+		}
+	}
+
+	color := opts.UseColor != "never"
+	termWidth := opts.IO.TerminalWidth()
+
+	for _, f := range files {
+		for _, line := range f.Header {
+			writeHeaderLine(w, line, color)
+		}
+		path := fileDiffPath(f)
+		for _, h := range f.Hunks {
+			switch opts.Style {
+			case styleSideBySide:
+				renderHunkSideBySide(w, h, color, path, hl, termWidth)
+			case styleWord:
+				renderHunkWordDiff(w, h, color, path, hl)
+			default:
+				renderHunkUnified(w, h, color, path, hl)
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeHeaderLine(w io.Writer, line string, color bool) {
+	if color {
+		fmt.Fprintf(w, "\x1b[1m%s\x1b[m\n", line)
+	} else {
+		fmt.Fprintln(w, line)
+	}
+}
+
+func hunkHeaderText(h *diffHunk) string {
+	text := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	if h.Heading != "" {
+		text += " " + h.Heading
+	}
+	return text
+}
+
+// contextKeepMask reports, for each line in lines, whether it survives
+// trimming to at most n lines of context on either side of each change,
+// mirroring `diff -U<n>`. It backs both trimContext and trimHunkContext.
+func contextKeepMask(lines []diffLine, n int) []bool {
+	// Find indexes of changed lines.
+	var changed []int
+	for i, l := range lines {
+		if l.Kind != ' ' {
+			changed = append(changed, i)
+		}
+	}
+
+	keep := make([]bool, len(lines))
+	if len(changed) == 0 {
+		if len(lines) > 2*n {
+			for i := 0; i < n; i++ {
+				keep[i] = true
+			}
+		} else {
+			for i := range keep {
+				keep[i] = true
+			}
+		}
+		return keep
+	}
+
+	for _, i := range changed {
+		keep[i] = true
+		for d := 1; d <= n; d++ {
+			if i-d >= 0 {
+				keep[i-d] = true
+			}
+			if i+d < len(lines) {
+				keep[i+d] = true
+			}
+		}
+	}
+	return keep
+}
+
+// trimContext reduces runs of unchanged (' ') lines surrounding changes down
+// to at most n lines on either side, mirroring `diff -U<n>`.
+func trimContext(lines []diffLine, n int) []diffLine {
+	keep := contextKeepMask(lines, n)
+	out := make([]diffLine, 0, len(lines))
+	for i, l := range lines {
+		if keep[i] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// trimHunkContext trims h.Lines the same way trimContext does, and
+// recomputes OldStart/OldLines/NewStart/NewLines from the surviving lines so
+// the "@@ ... @@" header hunkHeaderText prints matches the trimmed body
+// instead of going stale.
+func trimHunkContext(h *diffHunk, n int) {
+	keep := contextKeepMask(h.Lines, n)
+
+	oldStart, newStart := h.OldStart, h.NewStart
+	var oldLines, newLines int
+	trimmed := make([]diffLine, 0, len(h.Lines))
+	seenKept := false
+	for i, l := range h.Lines {
+		if !keep[i] {
+			if !seenKept {
+				if l.Kind != '+' {
+					oldStart++
+				}
+				if l.Kind != '-' {
+					newStart++
+				}
+			}
+			continue
+		}
+		seenKept = true
+		trimmed = append(trimmed, l)
+		if l.Kind != '+' {
+			oldLines++
+		}
+		if l.Kind != '-' {
+			newLines++
+		}
+	}
+
+	h.Lines = trimmed
+	h.OldStart, h.OldLines = oldStart, oldLines
+	h.NewStart, h.NewLines = newStart, newLines
+}
+
+func renderHunkUnified(w io.Writer, h *diffHunk, color bool, path string, hl *highlighter) {
+	writeHeaderLine(w, hunkHeaderText(h), color)
+	for _, l := range h.Lines {
+		writeDiffLine(w, l.Kind, l.Text, color, path, hl)
+	}
+}
+
+func writeDiffLine(w io.Writer, kind byte, text string, color bool, path string, hl *highlighter) {
+	if !color {
+		fmt.Fprintf(w, "%c%s\n", kind, text)
+		return
+	}
+	if hl == nil {
+		switch kind {
+		case '+':
+			fmt.Fprintf(w, "\x1b[32m+%s\x1b[m\n", text)
+		case '-':
+			fmt.Fprintf(w, "\x1b[31m-%s\x1b[m\n", text)
+		default:
+			fmt.Fprintf(w, " %s\n", text)
+		}
+		return
+	}
+
+	content := highlightLine(hl, path, text)
+	switch kind {
+	case '+':
+		fmt.Fprintf(w, "\x1b[32m+\x1b[m%s\n", content)
+	case '-':
+		fmt.Fprintf(w, "\x1b[31m-\x1b[m%s\n", content)
+	default:
+		fmt.Fprintf(w, " %s\n", content)
+	}
+}
+
+// renderHunkSideBySide lays a hunk out in two columns sized to termWidth,
+// pairing consecutive removed/added lines and padding the shorter side with
+// blanks. renderHunkSideBySide does not apply syntax highlighting: its
+// fixed-width columns are truncated and padded by byte length, which
+// chroma's ANSI escapes would throw off.
+func renderHunkSideBySide(w io.Writer, h *diffHunk, color bool, _ string, _ *highlighter, termWidth int) {
+	writeHeaderLine(w, hunkHeaderText(h), color)
+
+	if termWidth <= 0 {
+		termWidth = 80
+	}
+	colWidth := termWidth/2 - 2
+	if colWidth < 1 {
+		colWidth = 1
+	}
+
+	for _, pair := range pairHunkLines(h.Lines) {
+		left := formatColumn(pair.left, colWidth, '-', color)
+		right := formatColumn(pair.right, colWidth, '+', color)
+		fmt.Fprintf(w, "%s │ %s\n", left, right)
+	}
+}
+
+type linePair struct {
+	left, right *diffLine
+}
+
+// pairHunkLines walks a hunk's lines and pairs up consecutive '-' and '+'
+// runs left-to-right; context lines are emitted on both sides unchanged.
+func pairHunkLines(lines []diffLine) []linePair {
+	var pairs []linePair
+	i := 0
+	for i < len(lines) {
+		if lines[i].Kind == ' ' {
+			l := lines[i]
+			pairs = append(pairs, linePair{left: &l, right: &l})
+			i++
+			continue
+		}
+
+		var removed, added []diffLine
+		for i < len(lines) && lines[i].Kind == '-' {
+			removed = append(removed, lines[i])
+			i++
+		}
+		for i < len(lines) && lines[i].Kind == '+' {
+			added = append(added, lines[i])
+			i++
+		}
+
+		for n := 0; n < max(len(removed), len(added)); n++ {
+			var p linePair
+			if n < len(removed) {
+				p.left = &removed[n]
+			}
+			if n < len(added) {
+				p.right = &added[n]
+			}
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs
+}
+
+func formatColumn(l *diffLine, width int, marker byte, color bool) string {
+	if l == nil {
+		return strings.Repeat(" ", width)
+	}
+	prefix := " "
+	if l.Kind != ' ' {
+		prefix = string(marker)
+	}
+	text := prefix + l.Text
+	if len(text) > width {
+		text = text[:width]
+	}
+	padded := text + strings.Repeat(" ", width-len(text))
+	if !color || l.Kind == ' ' {
+		return padded
+	}
+	if l.Kind == '+' {
+		return "\x1b[32m" + padded + "\x1b[m"
+	}
+	return "\x1b[31m" + padded + "\x1b[m"
+}
+
+// renderHunkWordDiff highlights only the differing token runs within paired
+// '-'/'+' lines, using an LCS over whitespace-split tokens.
+// Paired lines are not syntax-highlighted: their token-level underlining
+// already marks up the text, and layering chroma's ANSI colors underneath
+// would fight the word-diff markup over the same runes. Unpaired and context
+// lines go through writeDiffLine and are highlighted when hl is non-nil.
+func renderHunkWordDiff(w io.Writer, h *diffHunk, color bool, path string, hl *highlighter) {
+	writeHeaderLine(w, hunkHeaderText(h), color)
+
+	for _, pair := range pairHunkLines(h.Lines) {
+		switch {
+		case pair.left != nil && pair.right != nil && pair.left.Kind != ' ':
+			writeWordDiffLine(w, '-', pair.left.Text, pair.right.Text, color)
+			writeWordDiffLine(w, '+', pair.right.Text, pair.left.Text, color)
+		case pair.left != nil:
+			writeDiffLine(w, pair.left.Kind, pair.left.Text, color, path, hl)
+		case pair.right != nil:
+			writeDiffLine(w, pair.right.Kind, pair.right.Text, color, path, hl)
+		}
+	}
+}
+
+func writeWordDiffLine(w io.Writer, kind byte, text, other string, color bool) {
+	if !color {
+		fmt.Fprintf(w, "%c%s\n", kind, text)
+		return
+	}
+	baseColor := "\x1b[31m"
+	if kind == '+' {
+		baseColor = "\x1b[32m"
+	}
+
+	tokens := strings.Fields(text)
+	otherTokens := strings.Fields(other)
+	changedTokens := diffTokenRuns(otherTokens, tokens)
+
+	fmt.Fprintf(w, "%c%s", kind, baseColor)
+	for i, tok := range tokens {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+		if changedTokens[i] {
+			fmt.Fprintf(w, "\x1b[1;4m%s\x1b[22;24m%s", tok, baseColor)
+		} else {
+			fmt.Fprint(w, tok)
+		}
+	}
+	fmt.Fprint(w, "\x1b[m\n")
+}
+
+// diffTokenRuns returns, for each token in b, whether it is part of a run
+// not present in the longest common subsequence between a and b.
+func diffTokenRuns(a, b []string) []bool {
+	lcs := lcsTokens(a, b)
+	result := make([]bool, len(b))
+	li := 0
+	for i, tok := range b {
+		if li < len(lcs) && lcs[li] == tok {
+			li++
+		} else {
+			result[i] = true
+		}
+	}
+	return result
+}
+
+func lcsTokens(a, b []string) []string {
+	n, m := len(a), len(b)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] >= dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	files, err := parseUnifiedDiff(strings.NewReader(testDiff))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if got := fileDiffPath(files[1]); got != "Makefile" {
+		t.Errorf("expected path %q, got %q", "Makefile", got)
+	}
+	if len(files[1].Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(files[1].Hunks))
+	}
+}
+
+func TestTrimContext(t *testing.T) {
+	lines := []diffLine{
+		{Kind: ' ', Text: "a"},
+		{Kind: ' ', Text: "b"},
+		{Kind: ' ', Text: "c"},
+		{Kind: '-', Text: "d"},
+		{Kind: '+', Text: "e"},
+		{Kind: ' ', Text: "f"},
+		{Kind: ' ', Text: "g"},
+		{Kind: ' ', Text: "h"},
+	}
+
+	trimmed := trimContext(lines, 1)
+	var texts []string
+	for _, l := range trimmed {
+		texts = append(texts, l.Text)
+	}
+	want := "c d e f"
+	if got := strings.Join(texts, " "); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestTrimHunkContext(t *testing.T) {
+	h := &diffHunk{
+		OldStart: 22, OldLines: 8,
+		NewStart: 22, NewLines: 8,
+		Lines: []diffLine{
+			{Kind: ' ', Text: "go test ./..."},
+			{Kind: ' ', Text: ".PHONY: test"},
+			{Kind: ' ', Text: ""},
+			{Kind: '-', Text: "site:"},
+			{Kind: '-', Text: "\tgit clone https://github.com/github/cli.github.com.git \"$@\""},
+			{Kind: '+', Text: "site: bin/gh"},
+			{Kind: '+', Text: "\tbin/gh repo clone github/cli.github.com \"$@\""},
+			{Kind: ' ', Text: ""},
+		},
+	}
+
+	trimHunkContext(h, 0)
+
+	if got := len(h.Lines); got != 4 {
+		t.Fatalf("expected 4 lines after trimming, got %d", got)
+	}
+	if h.OldStart != 25 || h.OldLines != 2 {
+		t.Errorf("expected old range 25,2, got %d,%d", h.OldStart, h.OldLines)
+	}
+	if h.NewStart != 25 || h.NewLines != 2 {
+		t.Errorf("expected new range 25,2, got %d,%d", h.NewStart, h.NewLines)
+	}
+	if want, got := "@@ -25,2 +25,2 @@", hunkHeaderText(h); got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+}
+
+func TestDiffTokenRuns(t *testing.T) {
+	a := strings.Fields("site: bin/gh")
+	b := strings.Fields("site: bin/gh extra")
+	runs := diffTokenRuns(a, b)
+	want := []bool{false, false, true}
+	if len(runs) != len(want) {
+		t.Fatalf("expected %d tokens, got %d", len(want), len(runs))
+	}
+	for i := range want {
+		if runs[i] != want[i] {
+			t.Errorf("token %d: expected %v, got %v", i, want[i], runs[i])
+		}
+	}
+}
@@ -2,10 +2,13 @@ package diff
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/cli/cli/context"
@@ -82,6 +85,22 @@ func TestPRDiff_validation(t *testing.T) {
 	assert.Equal(t, `did not understand color: "doublerainbow". Expected one of always, never, or auto`, err.Error())
 }
 
+func TestPRDiff_highlightValidation(t *testing.T) {
+	_, err := runCommand(nil, nil, false, "--highlight=rainbow")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	assert.Equal(t, `did not understand highlight: "rainbow". Expected one of auto, never, or always`, err.Error())
+}
+
+func TestPRDiff_formatAndApplyConflict(t *testing.T) {
+	_, err := runCommand(nil, nil, false, "--format=patch --apply")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	assert.Equal(t, `cannot use --format together with --apply`, err.Error())
+}
+
 func TestPRDiff_no_current_pr(t *testing.T) {
 	http := &httpmock.Registry{}
 	defer http.Verify(t)
@@ -156,6 +175,49 @@ func TestPRDiff_tty(t *testing.T) {
 	assert.Contains(t, output.String(), "\x1b[32m+site: bin/gh\x1b[m")
 }
 
+func TestPRDiff_tty_highlightAlways(t *testing.T) {
+	pager := os.Getenv("PAGER")
+	http := &httpmock.Registry{}
+	defer func() {
+		os.Setenv("PAGER", pager)
+		http.Verify(t)
+	}()
+	os.Setenv("PAGER", "")
+	http.StubResponse(200, bytes.NewBufferString(`
+		{ "data": { "repository": { "pullRequests": { "nodes": [
+			{ "url": "https://github.com/OWNER/REPO/pull/123",
+			  "number": 123,
+			  "id": "foobar123",
+			  "headRefName": "feature",
+				"baseRefName": "master" }
+		] } } } }`))
+	http.StubResponse(200, bytes.NewBufferString(testDiff))
+	output, err := runCommand(http, nil, true, "--highlight=always")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// chroma wraps each token in its own escape sequence, so find the line
+	// by its text with the escapes stripped.
+	ansiEscape := regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	var addedLine string
+	for _, line := range strings.Split(output.String(), "\n") {
+		if strings.Contains(ansiEscape.ReplaceAllString(line, ""), "site: bin/gh") {
+			addedLine = line
+			break
+		}
+	}
+	if addedLine == "" {
+		t.Fatal("expected the added site target line in the output")
+	}
+	if !strings.HasPrefix(addedLine, "\x1b[32m+\x1b[m") {
+		t.Errorf("expected the added-line marker to stay green, got %q", addedLine)
+	}
+	if addedLine == "\x1b[32m+\x1b[msite: bin/gh" {
+		t.Error("expected chroma syntax highlighting to tokenize the line content, got plain text")
+	}
+}
+
 func TestPRDiff_pager(t *testing.T) {
 	realRunPager := runPager
 	pager := os.Getenv("PAGER")
@@ -165,7 +227,10 @@ func TestPRDiff_pager(t *testing.T) {
 		os.Setenv("PAGER", pager)
 		http.Verify(t)
 	}()
+	// The pager must receive the fully rendered diff, not the raw upstream
+	// one, so wrap the output with a marker only the stub adds.
 	runPager = func(pager string, diff io.Reader, out io.Writer) error {
+		fmt.Fprint(out, "PAGED>")
 		_, err := io.Copy(out, diff)
 		return err
 	}
@@ -183,11 +248,81 @@ func TestPRDiff_pager(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
-	if diff := cmp.Diff(testDiff, output.String()); diff != "" {
-		t.Errorf("command output did not match:\n%s", diff)
+	if !strings.HasPrefix(output.String(), "PAGED>") {
+		t.Fatal("expected the diff to be piped through the pager")
+	}
+	assert.Contains(t, output.String(), "\x1b[32m+site: bin/gh\x1b[m")
+}
+
+func TestPRDiff_tty_sideBySide(t *testing.T) {
+	pager := os.Getenv("PAGER")
+	http := &httpmock.Registry{}
+	defer func() {
+		os.Setenv("PAGER", pager)
+		http.Verify(t)
+	}()
+	os.Setenv("PAGER", "")
+	http.StubResponse(200, bytes.NewBufferString(`
+		{ "data": { "repository": { "pullRequests": { "nodes": [
+			{ "url": "https://github.com/OWNER/REPO/pull/123",
+			  "number": 123,
+			  "id": "foobar123",
+			  "headRefName": "feature",
+				"baseRefName": "master" }
+		] } } } }`))
+	http.StubResponse(200, bytes.NewBufferString(testDiff))
+	output, err := runCommand(http, nil, true, "--style=side-by-side")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var row string
+	for _, line := range strings.Split(output.String(), "\n") {
+		if strings.Contains(line, "site: bin/gh") {
+			row = line
+			break
+		}
+	}
+	if row == "" {
+		t.Fatal("expected a side-by-side row pairing the removed and added site target")
+	}
+	sep := strings.Index(row, "│")
+	if sep == -1 {
+		t.Fatal("expected a column separator between the two sides")
+	}
+	if left := row[:sep]; !strings.Contains(left, "-site:") {
+		t.Errorf("expected the left column to show the removed line, got %q", left)
+	}
+	if right := row[sep:]; !strings.Contains(right, "+site: bin/gh") {
+		t.Errorf("expected the right column to show the added line, got %q", right)
 	}
 }
 
+func TestPRDiff_tty_wordDiff(t *testing.T) {
+	pager := os.Getenv("PAGER")
+	http := &httpmock.Registry{}
+	defer func() {
+		os.Setenv("PAGER", pager)
+		http.Verify(t)
+	}()
+	os.Setenv("PAGER", "")
+	http.StubResponse(200, bytes.NewBufferString(`
+		{ "data": { "repository": { "pullRequests": { "nodes": [
+			{ "url": "https://github.com/OWNER/REPO/pull/123",
+			  "number": 123,
+			  "id": "foobar123",
+			  "headRefName": "feature",
+				"baseRefName": "master" }
+		] } } } }`))
+	http.StubResponse(200, bytes.NewBufferString(testDiff))
+	output, err := runCommand(http, nil, true, "--style=word")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	assert.Contains(t, output.String(), "\x1b[1;4mbin/gh\x1b[22;24m")
+}
+
 const testDiff = `diff --git a/.github/workflows/releases.yml b/.github/workflows/releases.yml
 index 73974448..b7fc0154 100644
 --- a/.github/workflows/releases.yml